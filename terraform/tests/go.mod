@@ -4,5 +4,8 @@ go 1.21
 
 require (
 	github.com/gruntwork-io/terratest v0.46.8
+	github.com/hashicorp/hcl/v2 v2.9.1
+	github.com/hashicorp/terraform-json v0.13.0
 	github.com/stretchr/testify v1.8.4
+	github.com/zclconf/go-cty v1.9.1
 )
@@ -0,0 +1,262 @@
+package terraformtest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// BindOutputs reads every output of the applied module into the struct
+// pointed to by target. Fields map to outputs by their `tf` tag, or by the
+// snake_case form of the field name when untagged:
+//
+//	var out struct {
+//		BucketName string            `tf:"bucket_name"`
+//		Labels     map[string]string `tf:"labels"`
+//		SAEmail    *string           `tf:"terraform_sa_email"`
+//	}
+//	terraformtest.BindOutputs(t, options, &out)
+//
+// Strings, bools, numbers, slices, maps, nested structs for objects, and
+// pointers are supported. Outputs bound to pointer fields, or tagged
+// `tf:",optional"`, may be null or absent; all other missing outputs and
+// every type mismatch are reported together.
+func BindOutputs(t testing.TestingT, options *terraform.Options, target interface{}) {
+	require.NoError(t, BindOutputsE(t, options, target))
+}
+
+// BindOutputsE is BindOutputs returning an error instead of failing the test.
+func BindOutputsE(t testing.TestingT, options *terraform.Options, target interface{}) error {
+	out, err := terraform.OutputJsonE(t, options, "")
+	if err != nil {
+		return err
+	}
+
+	outputs := map[string]appliedOutput{}
+	if err := json.Unmarshal([]byte(out), &outputs); err != nil {
+		return fmt.Errorf("parsing terraform output: %w", err)
+	}
+
+	values := make(map[string]interface{}, len(outputs))
+	for name, output := range outputs {
+		values[name] = output.Value
+	}
+	return bindValues(values, target)
+}
+
+// bindValues binds decoded output values into target.
+func bindValues(values map[string]interface{}, target interface{}) error {
+	rv := reflect.ValueOf(target)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("BindOutputs target must be a non-nil pointer to a struct, got %T", target)
+	}
+
+	var errs []string
+	bindStruct(values, rv.Elem(), "output ", &errs)
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return errors.New("binding terraform outputs:\n  " + strings.Join(errs, "\n  "))
+	}
+	return nil
+}
+
+// bindStruct binds the attributes of an object (or the outputs of a module)
+// into the fields of a struct. path prefixes every error message.
+func bindStruct(values map[string]interface{}, rv reflect.Value, path string, errs *[]string) {
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name, optional := fieldName(field)
+		if name == "-" {
+			continue
+		}
+		fieldPath := fmt.Sprintf("%s%q", path, name)
+
+		value, ok := values[name]
+		if !ok || value == nil {
+			if !optional && field.Type.Kind() != reflect.Pointer {
+				*errs = append(*errs, fieldPath+": missing or null")
+			}
+			continue
+		}
+		bindValue(value, rv.Field(i), fieldPath, errs)
+	}
+}
+
+func bindValue(value interface{}, rv reflect.Value, path string, errs *[]string) {
+	mismatch := func() {
+		*errs = append(*errs, fmt.Sprintf("%s: expected %s, got %s", path, rv.Type(), describe(value)))
+	}
+
+	// Lists and objects may hold nulls; only nullable fields can take them
+	if value == nil {
+		switch rv.Kind() {
+		case reflect.Interface, reflect.Pointer, reflect.Slice, reflect.Map:
+		default:
+			mismatch()
+		}
+		return
+	}
+
+	if rv.Kind() == reflect.Pointer {
+		elem := reflect.New(rv.Type().Elem())
+		bindValue(value, elem.Elem(), path, errs)
+		rv.Set(elem)
+		return
+	}
+
+	switch rv.Kind() {
+	case reflect.Interface:
+		if !reflect.TypeOf(value).AssignableTo(rv.Type()) {
+			mismatch()
+			return
+		}
+		rv.Set(reflect.ValueOf(value))
+
+	case reflect.String:
+		s, ok := value.(string)
+		if !ok {
+			mismatch()
+			return
+		}
+		rv.SetString(s)
+
+	case reflect.Bool:
+		b, ok := value.(bool)
+		if !ok {
+			mismatch()
+			return
+		}
+		rv.SetBool(b)
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// Range-check before converting; out-of-range conversions are undefined
+		n, ok := value.(float64)
+		if !ok || n != math.Trunc(n) || n < math.MinInt64 || n >= math.MaxInt64 || rv.OverflowInt(int64(n)) {
+			mismatch()
+			return
+		}
+		rv.SetInt(int64(n))
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := value.(float64)
+		if !ok || n < 0 || n != math.Trunc(n) || n >= math.MaxUint64 || rv.OverflowUint(uint64(n)) {
+			mismatch()
+			return
+		}
+		rv.SetUint(uint64(n))
+
+	case reflect.Float32, reflect.Float64:
+		n, ok := value.(float64)
+		if !ok {
+			mismatch()
+			return
+		}
+		rv.SetFloat(n)
+
+	case reflect.Slice:
+		list, ok := value.([]interface{})
+		if !ok {
+			mismatch()
+			return
+		}
+		slice := reflect.MakeSlice(rv.Type(), len(list), len(list))
+		for i, item := range list {
+			bindValue(item, slice.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+		rv.Set(slice)
+
+	case reflect.Map:
+		object, ok := value.(map[string]interface{})
+		if !ok || rv.Type().Key().Kind() != reflect.String {
+			mismatch()
+			return
+		}
+		m := reflect.MakeMapWithSize(rv.Type(), len(object))
+		for key, item := range object {
+			elem := reflect.New(rv.Type().Elem()).Elem()
+			bindValue(item, elem, fmt.Sprintf("%s[%q]", path, key), errs)
+			m.SetMapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()), elem)
+		}
+		rv.Set(m)
+
+	case reflect.Struct:
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			mismatch()
+			return
+		}
+		bindStruct(object, rv, path+".", errs)
+
+	default:
+		*errs = append(*errs, fmt.Sprintf("%s: unsupported field type %s", path, rv.Type()))
+	}
+}
+
+// fieldName returns the output or attribute name for a struct field and
+// whether it was tagged optional.
+func fieldName(field reflect.StructField) (string, bool) {
+	name, opts, _ := strings.Cut(field.Tag.Get("tf"), ",")
+	if name == "" {
+		name = snakeCase(field.Name)
+	}
+	return name, opts == "optional"
+}
+
+// snakeCase converts a Go field name such as BucketSelfLink, SAEmail, or
+// EnabledAPIs to bucket_self_link, sa_email, or enabled_apis.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			lowerBefore := i > 0 && unicode.IsLower(runes[i-1])
+			acronymEnd := i > 0 && i+1 < len(runes) && unicode.IsUpper(runes[i-1]) && unicode.IsLower(runes[i+1]) &&
+				!isPluralSuffix(runes[i+1:])
+			if lowerBefore || acronymEnd {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// isPluralSuffix reports whether rest is the "s" of a plural acronym such as
+// APIs or IDs rather than the start of the next word.
+func isPluralSuffix(rest []rune) bool {
+	return rest[0] == 's' && (len(rest) == 1 || unicode.IsUpper(rest[1]))
+}
+
+// describe names the terraform type of a decoded JSON value.
+func describe(value interface{}) string {
+	switch value.(type) {
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case float64:
+		return fmt.Sprintf("number %v", value)
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "object"
+	case nil:
+		return "null"
+	}
+	return fmt.Sprintf("%T", value)
+}
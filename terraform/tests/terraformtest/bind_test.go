@@ -0,0 +1,136 @@
+package terraformtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decodeOutputs(t *testing.T, raw string) map[string]interface{} {
+	values := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal([]byte(raw), &values))
+	return values
+}
+
+func TestBindOutputs(t *testing.T) {
+	values := decodeOutputs(t, `{
+		"project_id": "demo",
+		"project_number": 123456789012,
+		"enabled_apis": ["iam.googleapis.com", "storage.googleapis.com"],
+		"service_account_emails": {"ci": "ci@demo.iam.gserviceaccount.com"},
+		"bucket": {"name": "demo-terraform-state", "versioning": true},
+		"terraform_sa_email": "terraform@demo.iam.gserviceaccount.com"
+	}`)
+
+	var out struct {
+		ProjectID            string
+		ProjectNumber        int64
+		EnabledAPIs          []string `tf:"enabled_apis"`
+		ServiceAccountEmails map[string]string
+		Bucket               struct {
+			Name       string
+			Versioning bool
+		}
+		TerraformSAEmail *string
+		DefaultSAEmail   *string `tf:"default_service_account_email"`
+	}
+	require.NoError(t, bindValues(values, &out))
+
+	assert.Equal(t, "demo", out.ProjectID)
+	assert.Equal(t, int64(123456789012), out.ProjectNumber)
+	assert.Equal(t, []string{"iam.googleapis.com", "storage.googleapis.com"}, out.EnabledAPIs)
+	assert.Equal(t, map[string]string{"ci": "ci@demo.iam.gserviceaccount.com"}, out.ServiceAccountEmails)
+	assert.Equal(t, "demo-terraform-state", out.Bucket.Name)
+	assert.True(t, out.Bucket.Versioning)
+	require.NotNil(t, out.TerraformSAEmail)
+	assert.Equal(t, "terraform@demo.iam.gserviceaccount.com", *out.TerraformSAEmail)
+	assert.Nil(t, out.DefaultSAEmail)
+}
+
+func TestBindOutputsReportsEveryMismatch(t *testing.T) {
+	values := decodeOutputs(t, `{
+		"project_id": ["demo"],
+		"project_number": 1.5,
+		"service_account_emails": {"ci": 42}
+	}`)
+
+	var out struct {
+		ProjectID            string
+		ProjectNumber        int
+		ServiceAccountEmails map[string]string
+		BucketName           string
+		BucketURL            string `tf:"bucket_url,optional"`
+	}
+	err := bindValues(values, &out)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `output "project_id": expected string, got list`)
+	assert.Contains(t, err.Error(), `output "project_number": expected int, got number 1.5`)
+	assert.Contains(t, err.Error(), `output "service_account_emails"["ci"]: expected string, got number 42`)
+	assert.Contains(t, err.Error(), `output "bucket_name": missing or null`)
+	assert.NotContains(t, err.Error(), "bucket_url")
+}
+
+func TestBindOutputsNullElements(t *testing.T) {
+	values := decodeOutputs(t, `{
+		"items": ["a", null],
+		"labels": {"env": "test", "owner": null},
+		"zones": [["us-central1-a"], null],
+		"names": ["a", null]
+	}`)
+
+	var out struct {
+		Items  []interface{}
+		Labels map[string]*string
+		Zones  [][]string
+		Names  []string `tf:",optional"`
+	}
+	err := bindValues(values, &out)
+	require.Error(t, err)
+	assert.Equal(t, "binding terraform outputs:\n  output \"names\"[1]: expected string, got null", err.Error())
+
+	assert.Equal(t, []interface{}{"a", nil}, out.Items)
+	require.NotNil(t, out.Labels["env"])
+	assert.Equal(t, "test", *out.Labels["env"])
+	assert.Nil(t, out.Labels["owner"])
+	assert.Equal(t, [][]string{{"us-central1-a"}, nil}, out.Zones)
+}
+
+func TestBindOutputsRejectsUnassignableAndOutOfRange(t *testing.T) {
+	values := decodeOutputs(t, `{
+		"name": "demo",
+		"big": 1e19,
+		"huge": 1e300,
+		"negative": -1e300
+	}`)
+
+	var out struct {
+		Name     fmt.Stringer
+		Big      int64
+		Huge     uint64
+		Negative int
+	}
+	err := bindValues(values, &out)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `output "name": expected fmt.Stringer, got string`)
+	assert.Contains(t, err.Error(), `output "big": expected int64, got number 1e+19`)
+	assert.Contains(t, err.Error(), `output "huge": expected uint64, got number 1e+300`)
+	assert.Contains(t, err.Error(), `output "negative": expected int, got number -1e+300`)
+	assert.Zero(t, out.Big)
+	assert.Zero(t, out.Huge)
+}
+
+func TestBindOutputsTarget(t *testing.T) {
+	var out struct{ ProjectID string }
+	assert.ErrorContains(t, bindValues(nil, out), "non-nil pointer to a struct")
+}
+
+func TestSnakeCase(t *testing.T) {
+	assert.Equal(t, "bucket_self_link", snakeCase("BucketSelfLink"))
+	assert.Equal(t, "project_id", snakeCase("ProjectID"))
+	assert.Equal(t, "terraform_sa_email", snakeCase("TerraformSAEmail"))
+	assert.Equal(t, "enabled_apis", snakeCase("EnabledAPIs"))
+	assert.Equal(t, "service_account_ids", snakeCase("ServiceAccountIDs"))
+}
@@ -0,0 +1,304 @@
+package terraformtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// BudgetEnvVar sets the ceiling of a test run in USD. Unset or zero
+	// means no ceiling; costs are still estimated and logged.
+	BudgetEnvVar = "TEST_BUDGET_USD"
+
+	// BudgetLedgerEnvVar names a file that carries spend across test
+	// processes, e.g. every package or rerun of a nightly matrix.
+	BudgetLedgerEnvVar = "TEST_BUDGET_LEDGER"
+)
+
+// CostEstimator prices the resources a module kept alive for a duration.
+// Implementations may use resource-based rates, as HourlyRates does, or
+// query a billing export.
+type CostEstimator interface {
+	Estimate(resources []*tfjson.StateResource, alive time.Duration) float64
+}
+
+// HourlyRates estimates cost from a flat USD rate per resource type and
+// started hour. Unlisted resource types are treated as free.
+type HourlyRates map[string]float64
+
+// DefaultHourlyRates are deliberately pessimistic ballpark rates for the
+// resource types that dominate the cost of a test run.
+var DefaultHourlyRates = HourlyRates{
+	"google_compute_instance":        0.10,
+	"google_compute_router_nat":      0.05,
+	"google_compute_forwarding_rule": 0.03,
+	"google_container_cluster":       0.10,
+	"google_container_node_pool":     0.30,
+	"google_sql_database_instance":   0.20,
+	"google_redis_instance":          0.10,
+	"google_storage_bucket":          0.01,
+	"google_cloud_run_v2_service":    0.01,
+}
+
+// Estimate implements CostEstimator.
+func (r HourlyRates) Estimate(resources []*tfjson.StateResource, alive time.Duration) float64 {
+	hours := math.Max(1, math.Ceil(alive.Hours()))
+	var cost float64
+	for _, resource := range resources {
+		cost += r[resource.Type] * hours
+	}
+	return cost
+}
+
+// Budget meters the estimated spend of a test run and stops it once the
+// ceiling is reached. It is safe for use by parallel tests: each apply
+// reserves its estimated cost up front, so tests started together can't all
+// pass the check before any of them has spent anything.
+type Budget struct {
+	// Ceiling in USD; zero means unlimited.
+	Ceiling float64
+
+	// Estimator defaults to DefaultHourlyRates.
+	Estimator CostEstimator
+
+	// LedgerPath, when set, persists the cumulative spend across processes.
+	// Writes are not coordinated between processes running at the same time.
+	LedgerPath string
+
+	mu       sync.Mutex
+	spent    float64
+	reserved float64
+	perTest  map[string]float64
+}
+
+// NewBudget creates a budget with the given ceiling using DefaultHourlyRates.
+func NewBudget(ceiling float64) *Budget {
+	return &Budget{Ceiling: ceiling, Estimator: DefaultHourlyRates, perTest: map[string]float64{}}
+}
+
+// BudgetFromEnv creates a budget from TEST_BUDGET_USD and TEST_BUDGET_LEDGER.
+func BudgetFromEnv() *Budget {
+	ceiling, _ := strconv.ParseFloat(os.Getenv(BudgetEnvVar), 64)
+	budget := NewBudget(ceiling)
+	budget.LedgerPath = os.Getenv(BudgetLedgerEnvVar)
+	return budget
+}
+
+// Meter fails the test at once if the budget is already spent, so nothing
+// more gets applied. With a ceiling set, it then plans the module, reserves
+// the cost of the planned resources for an hour, and fails if that would
+// cross the ceiling. Otherwise it returns a function to defer after the
+// destroy is deferred, so it runs while the resources still exist:
+//
+//	defer terraform.Destroy(t, options)
+//	defer budget.Meter(t, options)()
+//	terraform.InitAndApply(t, options)
+//
+// The returned function estimates the cost of the module's resources, logs
+// it with the run total, releases the reservation, and fails the test once
+// the ceiling is crossed. Deferred destroys still run, so aborting never
+// leaks resources. A nil Budget meters nothing.
+func (b *Budget) Meter(t testing.TestingT, options *terraform.Options) func() {
+	if b == nil {
+		return func() {}
+	}
+
+	committed, err := b.committed()
+	require.NoError(t, err)
+	if b.exceeded(committed) {
+		require.FailNow(t, "test budget exhausted", "spent or reserved ~$%.2f of $%.2f, not applying %s", committed, b.Ceiling, options.TerraformDir)
+		return func() {}
+	}
+
+	var estimate float64
+	if b.Ceiling > 0 {
+		estimate, err = b.planEstimateE(t, options)
+		require.NoError(t, err)
+
+		committed, ok, err := b.reserve(estimate)
+		require.NoError(t, err)
+		if !ok {
+			require.FailNow(t, "test budget exhausted", "spent or reserved ~$%.2f of $%.2f, not applying %s for another ~$%.2f", committed, b.Ceiling, options.TerraformDir, estimate)
+			return func() {}
+		}
+		logger.Default.Logf(t, "Reserved ~$%.2f of the test budget for %s", estimate, options.TerraformDir)
+	}
+
+	start := time.Now()
+	return func() {
+		defer b.release(estimate)
+
+		// Count the reservation if the state can't be read
+		cost := estimate
+		if values, err := stateValuesE(t, options); err != nil {
+			logger.Default.Logf(t, "Cannot read state of %s to estimate cost, counting ~$%.2f: %v", options.TerraformDir, cost, err)
+		} else {
+			cost = b.estimator().Estimate(ManagedResources(values), time.Since(start))
+		}
+
+		testCost, total, err := b.record(t.Name(), cost)
+		require.NoError(t, err)
+
+		logger.Default.Logf(t, "Estimated cost: ~$%.2f this module, ~$%.2f this test, ~$%.2f this run", cost, testCost, total)
+		if b.exceeded(total) {
+			require.FailNow(t, "test budget exhausted", "spent ~$%.2f of $%.2f, aborting remaining tests", total, b.Ceiling)
+		}
+	}
+}
+
+// planEstimateE prices the resources the module will have once applied.
+func (b *Budget) planEstimateE(t testing.TestingT, options *terraform.Options) (float64, error) {
+	dir, err := os.MkdirTemp("", "terratest-budget")
+	if err != nil {
+		return 0, err
+	}
+	defer os.RemoveAll(dir)
+
+	planOptions, err := options.Clone()
+	if err != nil {
+		return 0, err
+	}
+	planOptions.PlanFilePath = filepath.Join(dir, "budget.plan")
+
+	plan, err := terraform.InitAndPlanAndShowWithStructE(t, planOptions)
+	if err != nil {
+		return 0, err
+	}
+	return b.estimator().Estimate(ManagedResources(plan.RawPlan.PlannedValues), 0), nil
+}
+
+// estimator returns the Estimator, or DefaultHourlyRates for a Budget built
+// without one.
+func (b *Budget) estimator() CostEstimator {
+	if b.Estimator == nil {
+		return DefaultHourlyRates
+	}
+	return b.Estimator
+}
+
+// stateValuesE reads the applied state even when options point at a plan file.
+func stateValuesE(t testing.TestingT, options *terraform.Options) (*tfjson.StateValues, error) {
+	stateOptions, err := options.Clone()
+	if err != nil {
+		return nil, err
+	}
+	stateOptions.PlanFilePath = ""
+
+	stateJSON, err := terraform.ShowE(t, stateOptions)
+	if err != nil {
+		return nil, err
+	}
+	return StateValuesE(stateJSON)
+}
+
+func (b *Budget) exceeded(total float64) bool {
+	return b.Ceiling > 0 && total >= b.Ceiling
+}
+
+// total returns the spend so far, including other processes sharing the ledger.
+func (b *Budget) total() (float64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.totalLocked()
+}
+
+func (b *Budget) totalLocked() (float64, error) {
+	if b.LedgerPath == "" {
+		return b.spent, nil
+	}
+	return readLedger(b.LedgerPath)
+}
+
+// committed returns the spend so far plus the outstanding reservations of
+// this process.
+func (b *Budget) committed() (float64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	spent, err := b.totalLocked()
+	return spent + b.reserved, err
+}
+
+// reserve holds estimate against the ceiling until it is released. It returns
+// the amount already committed and false if estimate doesn't fit.
+func (b *Budget) reserve(estimate float64) (float64, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	spent, err := b.totalLocked()
+	if err != nil {
+		return 0, false, err
+	}
+	committed := spent + b.reserved
+	if b.exceeded(committed) || b.Ceiling > 0 && committed+estimate > b.Ceiling {
+		return committed, false, nil
+	}
+	b.reserved += estimate
+	return committed, true, nil
+}
+
+func (b *Budget) release(estimate float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.reserved -= estimate
+}
+
+// record adds cost to a test and the run, returning both totals.
+func (b *Budget) record(test string, cost float64) (float64, float64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.perTest == nil {
+		b.perTest = map[string]float64{}
+	}
+	b.perTest[test] += cost
+	b.spent += cost
+
+	if b.LedgerPath == "" {
+		return b.perTest[test], b.spent, nil
+	}
+
+	total, err := readLedger(b.LedgerPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	total += cost
+	data, err := json.Marshal(ledger{SpentUSD: total})
+	if err != nil {
+		return 0, 0, err
+	}
+	return b.perTest[test], total, os.WriteFile(b.LedgerPath, data, 0o644)
+}
+
+// ledger is the file format behind TEST_BUDGET_LEDGER.
+type ledger struct {
+	SpentUSD float64 `json:"spent_usd"`
+}
+
+func readLedger(path string) (float64, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var l ledger
+	if err := json.Unmarshal(data, &l); err != nil {
+		return 0, fmt.Errorf("parsing budget ledger %s: %w", path, err)
+	}
+	return l.SpentUSD, nil
+}
@@ -0,0 +1,155 @@
+package terraformtest
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHourlyRatesEstimate(t *testing.T) {
+	resources := []*tfjson.StateResource{
+		{Type: "google_compute_instance"},
+		{Type: "google_compute_instance"},
+		{Type: "google_service_account"},
+	}
+	rates := HourlyRates{"google_compute_instance": 0.5}
+
+	// Billed per started hour, with at least one hour.
+	assert.InDelta(t, 1.0, rates.Estimate(resources, 10*time.Minute), 1e-9)
+	assert.InDelta(t, 2.0, rates.Estimate(resources, 61*time.Minute), 1e-9)
+}
+
+func TestBudgetRecord(t *testing.T) {
+	budget := NewBudget(5)
+
+	testCost, total, err := budget.record("TestA", 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2.0, testCost)
+	assert.Equal(t, 2.0, total)
+	assert.False(t, budget.exceeded(total))
+
+	testCost, total, err = budget.record("TestB", 3)
+	require.NoError(t, err)
+	assert.Equal(t, 3.0, testCost)
+	assert.Equal(t, 5.0, total)
+	assert.True(t, budget.exceeded(total))
+
+	assert.False(t, NewBudget(0).exceeded(1000), "zero ceiling is unlimited")
+}
+
+func TestBudgetLedgerSpansProcesses(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ledger.json")
+
+	first := NewBudget(10)
+	first.LedgerPath = path
+	_, _, err := first.record("TestA", 4)
+	require.NoError(t, err)
+
+	// A second budget, as in another test process, continues from the ledger.
+	second := NewBudget(10)
+	second.LedgerPath = path
+	spent, err := second.total()
+	require.NoError(t, err)
+	assert.Equal(t, 4.0, spent)
+
+	_, total, err := second.record("TestB", 1.5)
+	require.NoError(t, err)
+	assert.Equal(t, 5.5, total)
+}
+
+// fakeT records failures. Like testing.T, FailNow stops the calling goroutine,
+// so code under test must be called through run.
+type fakeT struct {
+	failed   bool
+	messages []string
+}
+
+// run calls fn on its own goroutine and waits for it to return or stop.
+func (f *fakeT) run(fn func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	<-done
+}
+
+func (f *fakeT) Fail()                                     { f.failed = true }
+func (f *fakeT) FailNow()                                  { f.failed = true; runtime.Goexit() }
+func (f *fakeT) Fatal(args ...interface{})                 { f.FailNow() }
+func (f *fakeT) Fatalf(format string, args ...interface{}) { f.FailNow() }
+func (f *fakeT) Error(args ...interface{})                 { f.failed = true }
+func (f *fakeT) Errorf(format string, args ...interface{}) {
+	f.failed = true
+	f.messages = append(f.messages, fmt.Sprintf(format, args...))
+}
+func (f *fakeT) Name() string { return "fake" }
+
+func TestBudgetMeterRefusesWhenExhausted(t *testing.T) {
+	budget := NewBudget(1)
+	_, _, err := budget.record("TestEarlier", 1)
+	require.NoError(t, err)
+
+	// Must stop before planning, which would run terraform in the module
+	ft := &fakeT{}
+	planned := false
+	ft.run(func() {
+		budget.Meter(ft, &terraform.Options{TerraformDir: "../../modules/state-backend"})
+		planned = true
+	})
+	assert.True(t, ft.failed)
+	assert.False(t, planned)
+}
+
+func TestBudgetReservationsHoldCeilingForParallelTests(t *testing.T) {
+	budget := NewBudget(1)
+
+	// Two parallel applies each plan ~$0.60; only the first may start.
+	_, ok, err := budget.reserve(0.6)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	committed, ok, err := budget.reserve(0.6)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 0.6, committed)
+
+	// Once the first settles cheaper than reserved, the second fits.
+	_, _, err = budget.record("TestA", 0.2)
+	require.NoError(t, err)
+	budget.release(0.6)
+
+	_, ok, err = budget.reserve(0.6)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	committed, err = budget.committed()
+	require.NoError(t, err)
+	assert.InDelta(t, 0.8, committed, 1e-9)
+
+	_, ok, err = NewBudget(0).reserve(1000)
+	require.NoError(t, err)
+	assert.True(t, ok, "zero ceiling is unlimited")
+}
+
+func TestNilBudgetMetersNothing(t *testing.T) {
+	var budget *Budget
+	ft := &fakeT{}
+	ft.run(func() { budget.Meter(ft, &terraform.Options{TerraformDir: "../../modules/state-backend"})() })
+	assert.False(t, ft.failed)
+}
+
+func TestBudgetWithoutEstimatorUsesDefaultRates(t *testing.T) {
+	budget := &Budget{Ceiling: 5}
+	assert.Equal(t, DefaultHourlyRates, budget.estimator())
+
+	rates := HourlyRates{"google_compute_instance": 1}
+	budget.Estimator = rates
+	assert.Equal(t, rates, budget.estimator())
+}
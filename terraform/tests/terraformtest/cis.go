@@ -0,0 +1,310 @@
+package terraformtest
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// ComplianceReportDirEnvVar overrides where compliance reports are written.
+const ComplianceReportDirEnvVar = "COMPLIANCE_REPORT_DIR"
+
+const defaultComplianceReportDir = "compliance-reports"
+
+// Gcloud runs gcloud with the given arguments and returns its JSON output.
+type Gcloud func(args ...string) (string, error)
+
+// Check statuses in a compliance report.
+const (
+	StatusPass          = "PASS"
+	StatusFail          = "FAIL"
+	StatusError         = "ERROR"
+	StatusNotApplicable = "N/A"
+)
+
+// ErrNotApplicable is returned, wrapped, by Evaluate when the control doesn't
+// apply to the project, e.g. because the API it covers is disabled.
+var ErrNotApplicable = errors.New("not applicable")
+
+// BenchmarkCheck is one control evaluated against a live project.
+type BenchmarkCheck struct {
+	ID    string
+	Title string
+
+	// Evaluate returns why the project fails the control, or "" if it
+	// passes. An error means the control could not be evaluated, unless it
+	// wraps ErrNotApplicable.
+	Evaluate func(project string, gcloud Gcloud) (string, error)
+}
+
+// CheckResult is the outcome of one check in a compliance report.
+type CheckResult struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Status string `json:"status"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// ComplianceReport is the artifact written for every benchmark run.
+type ComplianceReport struct {
+	Project   string        `json:"project"`
+	Test      string        `json:"test"`
+	Timestamp time.Time     `json:"timestamp"`
+	Results   []CheckResult `json:"results"`
+}
+
+// Failures returns the results that neither passed nor were not applicable.
+func (r ComplianceReport) Failures() []CheckResult {
+	var failures []CheckResult
+	for _, result := range r.Results {
+		if result.Status != StatusPass && result.Status != StatusNotApplicable {
+			failures = append(failures, result)
+		}
+	}
+	return failures
+}
+
+// Key controls of the CIS Google Cloud Platform Foundation Benchmark that
+// apply to any project a module creates.
+var (
+	CISAuditLogging = BenchmarkCheck{
+		ID:       "CIS 2.1",
+		Title:    "Cloud Audit Logging is configured for all services and all users",
+		Evaluate: checkAuditLogging,
+	}
+	CISNoDefaultNetwork = BenchmarkCheck{
+		ID:       "CIS 3.1",
+		Title:    "The default network does not exist in a project",
+		Evaluate: checkNoDefaultNetwork,
+	}
+	CISOSLogin = BenchmarkCheck{
+		ID:       "CIS 4.4",
+		Title:    "OS Login is enabled for the project",
+		Evaluate: checkOSLogin,
+	}
+	CISUniformBucketAccess = BenchmarkCheck{
+		ID:       "CIS 5.2",
+		Title:    "Cloud Storage buckets have uniform bucket-level access enabled",
+		Evaluate: checkUniformBucketAccess,
+	}
+
+	CISChecks = []BenchmarkCheck{CISAuditLogging, CISNoDefaultNetwork, CISOSLogin, CISUniformBucketAccess}
+)
+
+// AssertCISBenchmark evaluates the checks (CISChecks if none are given)
+// against project using gcloud, writes a compliance report artifact, and
+// fails the test listing every control that did not pass.
+func AssertCISBenchmark(t testing.TestingT, project string, checks ...BenchmarkCheck) {
+	if len(checks) == 0 {
+		checks = CISChecks
+	}
+
+	report := RunBenchmark(project, shellGcloud(t), checks)
+	report.Test = t.Name()
+
+	path, err := WriteComplianceReportE(report)
+	require.NoError(t, err)
+	logger.Default.Logf(t, "Wrote compliance report %s", path)
+
+	var failures []string
+	for _, result := range report.Failures() {
+		failures = append(failures, fmt.Sprintf("%s %s: %s %s", result.Status, result.ID, result.Title, result.Detail))
+	}
+	require.Empty(t, failures, "project %s does not meet the benchmark", project)
+}
+
+// RunBenchmark evaluates every check against project.
+func RunBenchmark(project string, gcloud Gcloud, checks []BenchmarkCheck) ComplianceReport {
+	report := ComplianceReport{Project: project, Timestamp: time.Now().UTC()}
+	for _, check := range checks {
+		result := CheckResult{ID: check.ID, Title: check.Title, Status: StatusPass}
+		detail, err := check.Evaluate(project, gcloud)
+		switch {
+		case errors.Is(err, ErrNotApplicable):
+			result.Status, result.Detail = StatusNotApplicable, err.Error()
+		case err != nil:
+			result.Status, result.Detail = StatusError, err.Error()
+		case detail != "":
+			result.Status, result.Detail = StatusFail, detail
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report
+}
+
+// WriteComplianceReportE writes the report as JSON to COMPLIANCE_REPORT_DIR
+// (default compliance-reports) and returns its path.
+func WriteComplianceReportE(report ComplianceReport) (string, error) {
+	dir := os.Getenv(ComplianceReportDirEnvVar)
+	if dir == "" {
+		dir = defaultComplianceReportDir
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	name := strings.NewReplacer("/", "-", " ", "-").Replace(report.Test + "-" + report.Project)
+	path := filepath.Join(dir, name+".json")
+
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, data, 0o644)
+}
+
+func shellGcloud(t testing.TestingT) Gcloud {
+	return func(args ...string) (string, error) {
+		return shell.RunCommandAndGetStdOutE(t, shell.Command{
+			Command: "gcloud",
+			Args:    append(args, "--format=json"),
+			Logger:  logger.Discard,
+		})
+	}
+}
+
+func checkAuditLogging(project string, gcloud Gcloud) (string, error) {
+	out, err := gcloud("projects", "get-iam-policy", project)
+	if err != nil {
+		return "", err
+	}
+
+	var policy struct {
+		AuditConfigs []struct {
+			Service         string `json:"service"`
+			AuditLogConfigs []struct {
+				LogType         string   `json:"logType"`
+				ExemptedMembers []string `json:"exemptedMembers"`
+			} `json:"auditLogConfigs"`
+		} `json:"auditConfigs"`
+	}
+	if err := json.Unmarshal([]byte(out), &policy); err != nil {
+		return "", fmt.Errorf("parsing IAM policy: %w", err)
+	}
+
+	missing := map[string]bool{"ADMIN_READ": true, "DATA_READ": true, "DATA_WRITE": true}
+	var exempted []string
+	for _, config := range policy.AuditConfigs {
+		if config.Service != "allServices" {
+			continue
+		}
+		for _, log := range config.AuditLogConfigs {
+			delete(missing, log.LogType)
+			exempted = append(exempted, log.ExemptedMembers...)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Sprintf("allServices audit config lacks %s", strings.Join(sortedKeys(missing), ", ")), nil
+	}
+	if len(exempted) > 0 {
+		return fmt.Sprintf("audit logging exempts %s", strings.Join(exempted, ", ")), nil
+	}
+	return "", nil
+}
+
+func checkNoDefaultNetwork(project string, gcloud Gcloud) (string, error) {
+	out, err := gcloud("compute", "networks", "list", "--project", project, "--filter=name=default")
+	if err != nil && serviceDisabled(err) {
+		// Without the Compute API a project has no networks at all
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var networks []struct {
+		Name string `json:"name"`
+	}
+	if err := json.Unmarshal([]byte(out), &networks); err != nil {
+		return "", fmt.Errorf("parsing networks: %w", err)
+	}
+	if len(networks) > 0 {
+		return "default network exists", nil
+	}
+	return "", nil
+}
+
+func checkOSLogin(project string, gcloud Gcloud) (string, error) {
+	out, err := gcloud("compute", "project-info", "describe", "--project", project)
+	if err != nil && serviceDisabled(err) {
+		// No instances can exist to log in to
+		return "", fmt.Errorf("%w: Compute Engine API is disabled", ErrNotApplicable)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var info struct {
+		CommonInstanceMetadata struct {
+			Items []struct {
+				Key   string `json:"key"`
+				Value string `json:"value"`
+			} `json:"items"`
+		} `json:"commonInstanceMetadata"`
+	}
+	if err := json.Unmarshal([]byte(out), &info); err != nil {
+		return "", fmt.Errorf("parsing project info: %w", err)
+	}
+
+	for _, item := range info.CommonInstanceMetadata.Items {
+		if item.Key == "enable-oslogin" && strings.EqualFold(item.Value, "true") {
+			return "", nil
+		}
+	}
+	return "project metadata does not set enable-oslogin=TRUE", nil
+}
+
+func checkUniformBucketAccess(project string, gcloud Gcloud) (string, error) {
+	out, err := gcloud("storage", "buckets", "list", "--project", project)
+	if err != nil {
+		return "", err
+	}
+
+	var buckets []struct {
+		Name                     string `json:"name"`
+		UniformBucketLevelAccess bool   `json:"uniform_bucket_level_access"`
+	}
+	if err := json.Unmarshal([]byte(out), &buckets); err != nil {
+		return "", fmt.Errorf("parsing buckets: %w", err)
+	}
+
+	var fine []string
+	for _, bucket := range buckets {
+		if !bucket.UniformBucketLevelAccess {
+			fine = append(fine, bucket.Name)
+		}
+	}
+	if len(fine) > 0 {
+		return fmt.Sprintf("fine-grained ACLs on %s", strings.Join(fine, ", ")), nil
+	}
+	return "", nil
+}
+
+// serviceDisabled reports whether a gcloud error says the API it called is not
+// enabled on the project.
+func serviceDisabled(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "SERVICE_DISABLED") ||
+		strings.Contains(msg, "has not been used in project") ||
+		strings.Contains(msg, "not enabled on project")
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
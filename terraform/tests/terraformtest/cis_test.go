@@ -0,0 +1,110 @@
+package terraformtest
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGcloud answers gcloud commands from canned JSON keyed by the first
+// three arguments.
+func fakeGcloud(responses map[string]string) Gcloud {
+	return func(args ...string) (string, error) {
+		key := strings.Join(args[:3], " ")
+		out, ok := responses[key]
+		if !ok {
+			return "", errors.New("unexpected gcloud command: " + key)
+		}
+		return out, nil
+	}
+}
+
+var compliantProject = map[string]string{
+	"projects get-iam-policy demo": `{"auditConfigs": [{"service": "allServices", "auditLogConfigs": [
+		{"logType": "ADMIN_READ"}, {"logType": "DATA_READ"}, {"logType": "DATA_WRITE"}]}]}`,
+	"compute networks list":         `[]`,
+	"compute project-info describe": `{"commonInstanceMetadata": {"items": [{"key": "enable-oslogin", "value": "TRUE"}]}}`,
+	"storage buckets list":          `[{"name": "demo-terraform-state", "uniform_bucket_level_access": true}]`,
+}
+
+func TestRunBenchmarkCompliantProject(t *testing.T) {
+	report := RunBenchmark("demo", fakeGcloud(compliantProject), CISChecks)
+
+	require.Len(t, report.Results, len(CISChecks))
+	assert.Empty(t, report.Failures())
+}
+
+func TestCISChecksWithComputeDisabled(t *testing.T) {
+	responses := map[string]string{}
+	for key, out := range compliantProject {
+		responses[key] = out
+	}
+	gcloud := func(args ...string) (string, error) {
+		if args[0] == "compute" {
+			return "", errors.New("error while running command: exit status 1; ERROR: (gcloud.compute) " +
+				"Compute Engine API has not been used in project 123456789012 before or it is disabled. reason: SERVICE_DISABLED")
+		}
+		return fakeGcloud(responses)(args...)
+	}
+
+	// A bootstrapped project has no Compute API: no default network, and
+	// OS Login does not apply
+	report := RunBenchmark("demo", gcloud, CISChecks)
+	assert.Empty(t, report.Failures())
+
+	statuses := map[string]string{}
+	for _, result := range report.Results {
+		statuses[result.ID] = result.Status
+	}
+	assert.Equal(t, StatusPass, statuses["CIS 3.1"])
+	assert.Equal(t, StatusNotApplicable, statuses["CIS 4.4"])
+
+	// Any other error still means the control could not be evaluated
+	report = RunBenchmark("demo", fakeGcloud(nil), []BenchmarkCheck{CISNoDefaultNetwork, CISOSLogin})
+	require.Len(t, report.Failures(), 2)
+	assert.Equal(t, StatusError, report.Failures()[0].Status)
+	assert.Equal(t, StatusError, report.Failures()[1].Status)
+}
+
+func TestRunBenchmarkReportsEveryFailure(t *testing.T) {
+	responses := map[string]string{
+		"projects get-iam-policy demo": `{"auditConfigs": [{"service": "allServices", "auditLogConfigs": [
+			{"logType": "ADMIN_READ"}, {"logType": "DATA_WRITE", "exemptedMembers": ["user:a@example.com"]}]}]}`,
+		"compute networks list": `[{"name": "default"}]`,
+		"storage buckets list":  `[{"name": "legacy", "uniform_bucket_level_access": false}, {"name": "ok", "uniform_bucket_level_access": true}]`,
+	}
+	report := RunBenchmark("demo", fakeGcloud(responses), CISChecks)
+
+	results := map[string]CheckResult{}
+	for _, result := range report.Failures() {
+		results[result.ID] = result
+	}
+	require.Len(t, results, 4)
+	assert.Equal(t, StatusFail, results["CIS 2.1"].Status)
+	assert.Equal(t, "allServices audit config lacks DATA_READ", results["CIS 2.1"].Detail)
+	assert.Equal(t, "default network exists", results["CIS 3.1"].Detail)
+	assert.Equal(t, StatusError, results["CIS 4.4"].Status)
+	assert.Equal(t, "fine-grained ACLs on legacy", results["CIS 5.2"].Detail)
+}
+
+func TestWriteComplianceReport(t *testing.T) {
+	t.Setenv(ComplianceReportDirEnvVar, t.TempDir())
+
+	report := RunBenchmark("demo", fakeGcloud(compliantProject), CISChecks)
+	report.Test = "TestProject/cis"
+	path, err := WriteComplianceReportE(report)
+	require.NoError(t, err)
+	assert.Contains(t, path, "TestProject-cis-demo.json")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var written ComplianceReport
+	require.NoError(t, json.Unmarshal(data, &written))
+	assert.Equal(t, "demo", written.Project)
+	assert.Len(t, written.Results, len(CISChecks))
+}
@@ -0,0 +1,192 @@
+package terraformtest
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/retry"
+	"github.com/gruntwork-io/terratest/modules/shell"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	// IAP tunnels and guest agents take a while to come up after apply.
+	connectivityRetries = 20
+	connectivityBackoff = 15 * time.Second
+
+	dialTimeout = 10 * time.Second
+)
+
+// Instance identifies a VM created by a compute module.
+type Instance struct {
+	Project string
+	Zone    string
+	Name    string
+}
+
+// RunOnInstance runs a shell command on the VM over SSH tunnelled through IAP,
+// so the VM needs neither an external IP nor an SSH firewall rule open to the
+// internet. It retries until the VM accepts the connection.
+func RunOnInstance(t testing.TestingT, vm Instance, command string) string {
+	out, err := RunOnInstanceE(t, vm, command)
+	require.NoError(t, err)
+	return out
+}
+
+// RunOnInstanceE runs a shell command on the VM over SSH tunnelled through IAP.
+func RunOnInstanceE(t testing.TestingT, vm Instance, command string) (string, error) {
+	cmd := shell.Command{
+		Command: "gcloud",
+		Args: []string{
+			"compute", "ssh", vm.Name,
+			"--project", vm.Project,
+			"--zone", vm.Zone,
+			"--tunnel-through-iap",
+			"--quiet",
+			"--command", command,
+		},
+	}
+
+	description := fmt.Sprintf("Running %q on %s over IAP", command, vm.Name)
+	return retry.DoWithRetryE(t, description, connectivityRetries, connectivityBackoff, func() (string, error) {
+		return shell.RunCommandAndGetStdOutE(t, cmd)
+	})
+}
+
+// AssertPrivateGoogleAccess fails the test if the VM cannot reach Google APIs.
+// Run it against a VM without an external IP or NAT so a response proves that
+// Private Google Access works. Any HTTP status counts, including 401.
+func AssertPrivateGoogleAccess(t testing.TestingT, vm Instance) {
+	AssertInstanceReachesURL(t, vm, "https://storage.googleapis.com/storage/v1/b")
+}
+
+// AssertNATEgress fails the test if the VM cannot reach a URL on the public
+// internet, which for a VM without an external IP means Cloud NAT is broken.
+func AssertNATEgress(t testing.TestingT, vm Instance, url string) {
+	AssertInstanceReachesURL(t, vm, url)
+}
+
+// AssertInstanceReachesURL fails the test if an HTTP request from the VM to
+// url gets no response at all.
+func AssertInstanceReachesURL(t testing.TestingT, vm Instance, url string) {
+	command := fmt.Sprintf("curl -s -o /dev/null -w '%%{http_code}' --max-time %d %s", int(dialTimeout.Seconds()), shellQuote(url))
+	status := strings.TrimSpace(RunOnInstance(t, vm, command))
+	require.Regexp(t, `^[1-5][0-9][0-9]$`, status, "%s could not reach %s", vm.Name, url)
+}
+
+// AssertExternalSSHDenied fails the test unless the firewall drops
+// connections from the test runner to port 22 of address. A refused
+// connection fails too: the packet got past the firewall to the host.
+//
+// address must be the VM's external IP, and openPort a port the firewall
+// allows and the VM answers on, e.g. 80 for a web server. It serves as a
+// positive control: if the runner can't reach openPort either, its own egress
+// is restricted and a timeout on port 22 would prove nothing.
+func AssertExternalSSHDenied(t testing.TestingT, address string, openPort string) {
+	require.NoError(t, ExternalSSHDeniedE(address, openPort, dialTimeout))
+}
+
+// ExternalSSHDeniedE is AssertExternalSSHDenied returning an error instead of
+// failing the test.
+func ExternalSSHDeniedE(address string, openPort string, timeout time.Duration) error {
+	ip := net.ParseIP(address)
+	if ip == nil {
+		return fmt.Errorf("%q is not an IP address; pass the VM's external IP", address)
+	}
+	if ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() {
+		return fmt.Errorf("%s is not an external IP, so the internet can't reach it whatever the firewall says", address)
+	}
+	return sshDeniedE(address, openPort, timeout)
+}
+
+// sshDeniedE checks the positive control on openPort, then port 22.
+func sshDeniedE(address string, openPort string, timeout time.Duration) error {
+	control := net.JoinHostPort(address, openPort)
+	conn, err := net.DialTimeout("tcp", control, timeout)
+	if err != nil {
+		return fmt.Errorf("positive control failed, the test runner cannot reach %s: %w", control, err)
+	}
+	conn.Close()
+
+	return PortFilteredE(net.JoinHostPort(address, "22"), timeout)
+}
+
+// PortFilteredE returns nil if a TCP connection to address times out or the
+// host is reported unreachable, the results of a firewall dropping or
+// rejecting the packet. A connection that is accepted or refused, or an
+// address that can't be dialled, is an error.
+func PortFilteredE(address string, timeout time.Duration) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if host == "" {
+		return fmt.Errorf("%s has no host; it would probe the test runner itself", address)
+	}
+
+	conn, err := net.DialTimeout("tcp", address, timeout)
+	if err == nil {
+		conn.Close()
+		return fmt.Errorf("%s accepted a connection, expected it to be blocked", address)
+	}
+	if filtered(err) {
+		return nil
+	}
+	if errors.Is(err, syscall.ECONNREFUSED) {
+		return fmt.Errorf("%s refused the connection, so the firewall let it through to the host", address)
+	}
+	return fmt.Errorf("cannot tell whether %s is blocked: %w", address, err)
+}
+
+// shellQuote quotes s as a single word for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// filtered reports whether a dial error is what a firewall causes: no answer
+// at all, or an ICMP unreachable from a rule that rejects.
+func filtered(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	return errors.Is(err, syscall.EHOSTUNREACH)
+}
+
+// AssertSerialPortContains waits until the VM's serial console output matches
+// pattern. Startup scripts can run connectivity checks themselves and print
+// a marker, which works even when SSH is deliberately unavailable.
+func AssertSerialPortContains(t testing.TestingT, vm Instance, pattern string) {
+	re := regexp.MustCompile(pattern)
+	cmd := shell.Command{
+		Command: "gcloud",
+		Args: []string{
+			"compute", "instances", "get-serial-port-output", vm.Name,
+			"--project", vm.Project,
+			"--zone", vm.Zone,
+			"--port", "1",
+		},
+		// The full console log is printed on every attempt otherwise
+		Logger: logger.Discard,
+	}
+
+	description := fmt.Sprintf("Waiting for %q in serial output of %s", pattern, vm.Name)
+	_, err := retry.DoWithRetryE(t, description, connectivityRetries, connectivityBackoff, func() (string, error) {
+		out, err := shell.RunCommandAndGetStdOutE(t, cmd)
+		if err != nil {
+			return "", err
+		}
+		if !re.MatchString(out) {
+			return "", fmt.Errorf("serial output of %s does not match %q yet", vm.Name, pattern)
+		}
+		return "", nil
+	})
+	require.NoError(t, err)
+}
@@ -0,0 +1,69 @@
+package terraformtest
+
+import (
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPortFilteredE(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+
+	assert.ErrorContains(t, PortFilteredE(address, time.Second), "accepted a connection")
+
+	// Nothing listens any more, so the host answers with a reset
+	require.NoError(t, listener.Close())
+	assert.ErrorContains(t, PortFilteredE(address, time.Second), "refused the connection")
+
+	assert.ErrorContains(t, PortFilteredE(":22", time.Second), "no host")
+	assert.ErrorContains(t, PortFilteredE("no-such-host.invalid:22", time.Second), "cannot tell whether")
+}
+
+func TestFiltered(t *testing.T) {
+	timeout := &net.OpError{Op: "dial", Net: "tcp", Err: timeoutError{}}
+	assert.True(t, filtered(timeout))
+
+	unreachable := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.EHOSTUNREACH)}
+	assert.True(t, filtered(unreachable))
+
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	assert.False(t, filtered(refused))
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestExternalSSHDeniedE(t *testing.T) {
+	assert.ErrorContains(t, ExternalSSHDeniedE("vm.example.com", "80", time.Second), "not an IP address")
+	assert.ErrorContains(t, ExternalSSHDeniedE("10.128.0.2", "80", time.Second), "not an external IP")
+	assert.ErrorContains(t, ExternalSSHDeniedE("127.0.0.1", "80", time.Second), "not an external IP")
+}
+
+func TestSSHDeniedNeedsPositiveControl(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	_, port, err := net.SplitHostPort(listener.Addr().String())
+	require.NoError(t, err)
+
+	// The control answers, and loopback port 22 is either open or refused
+	assert.Error(t, sshDeniedE("127.0.0.1", port, time.Second))
+	assert.NotContains(t, sshDeniedE("127.0.0.1", port, time.Second).Error(), "positive control")
+
+	require.NoError(t, listener.Close())
+	assert.ErrorContains(t, sshDeniedE("127.0.0.1", port, time.Second), "positive control failed")
+}
+
+func TestShellQuote(t *testing.T) {
+	assert.Equal(t, `'https://example.com/?a=1&b=2'`, shellQuote("https://example.com/?a=1&b=2"))
+	assert.Equal(t, `'it'\''s; rm -rf /'`, shellQuote("it's; rm -rf /"))
+}
@@ -0,0 +1,233 @@
+package terraformtest
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite golden plan snapshots instead of comparing against them")
+
+// Scrubber replaces volatile text in a plan snapshot, such as a randomized
+// project ID, with a stable placeholder.
+type Scrubber struct {
+	Pattern     *regexp.Regexp
+	Replacement string
+}
+
+// ScrubLiteral replaces every occurrence of value with placeholder.
+func ScrubLiteral(value string, placeholder string) Scrubber {
+	return Scrubber{Pattern: regexp.MustCompile(regexp.QuoteMeta(value)), Replacement: placeholder}
+}
+
+// DefaultScrubbers are applied to every snapshot before any caller scrubbers.
+var DefaultScrubbers = []Scrubber{
+	{
+		Pattern:     regexp.MustCompile(`\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})`),
+		Replacement: "<timestamp>",
+	},
+}
+
+// AssertPlanMatchesGolden compares a normalized snapshot of the plan with the
+// committed golden file and fails with a line diff if planned resources
+// changed. Run the tests with -update to write or rewrite the golden file; a
+// missing golden file fails the test so a mistyped path can't pass silently.
+func AssertPlanMatchesGolden(t testing.TestingT, plan *terraform.PlanStruct, goldenPath string, scrubbers ...Scrubber) {
+	snapshot, err := PlanSnapshot(plan, scrubbers...)
+	require.NoError(t, err)
+
+	if *updateGolden {
+		require.NoError(t, os.MkdirAll(filepath.Dir(goldenPath), 0o755))
+		require.NoError(t, os.WriteFile(goldenPath, []byte(snapshot), 0o644))
+		logger.Default.Logf(t, "Wrote golden plan %s", goldenPath)
+		return
+	}
+
+	golden, err := os.ReadFile(goldenPath)
+	if os.IsNotExist(err) {
+		require.FailNow(t, "golden plan missing", "%s does not exist; run the tests with -update to create it", goldenPath)
+		return
+	}
+	require.NoError(t, err)
+
+	if diff := lineDiff(string(golden), snapshot); diff != "" {
+		require.Fail(t, "plan does not match golden file", "%s (- golden, + actual; rerun with -update if intended):\n%s", goldenPath, diff)
+	}
+}
+
+// PlanSnapshot renders the resource changes and planned outputs of a plan as
+// stable, indented JSON. Unknown attributes and outputs are shown as
+// "(known after apply)". No-op changes, null attributes, and empty blocks are
+// left out, so optional attributes added by a provider release don't churn
+// the snapshot.
+func PlanSnapshot(plan *terraform.PlanStruct, scrubbers ...Scrubber) (string, error) {
+	type snapshotChange struct {
+		Address string                 `json:"address"`
+		Actions []string               `json:"actions"`
+		After   map[string]interface{} `json:"after,omitempty"`
+	}
+	snapshot := struct {
+		ResourceChanges []snapshotChange       `json:"resource_changes"`
+		Outputs         map[string]interface{} `json:"outputs,omitempty"`
+	}{ResourceChanges: []snapshotChange{}}
+
+	for _, rc := range plan.RawPlan.ResourceChanges {
+		if rc.Change == nil || rc.Change.Actions.NoOp() {
+			continue
+		}
+
+		change := snapshotChange{Address: rc.Address}
+		for _, action := range rc.Change.Actions {
+			change.Actions = append(change.Actions, string(action))
+		}
+		if after, ok := compact(rc.Change.After).(map[string]interface{}); ok {
+			change.After = after
+		}
+		if unknown, ok := rc.Change.AfterUnknown.(map[string]interface{}); ok {
+			for key, value := range unknown {
+				if isUnknown, _ := value.(bool); isUnknown {
+					if change.After == nil {
+						change.After = map[string]interface{}{}
+					}
+					change.After[key] = "(known after apply)"
+				}
+			}
+		}
+		snapshot.ResourceChanges = append(snapshot.ResourceChanges, change)
+	}
+	sort.Slice(snapshot.ResourceChanges, func(i, j int) bool {
+		return snapshot.ResourceChanges[i].Address < snapshot.ResourceChanges[j].Address
+	})
+
+	if plan.RawPlan.PlannedValues != nil && len(plan.RawPlan.PlannedValues.Outputs) > 0 {
+		snapshot.Outputs = map[string]interface{}{}
+		for name, output := range plan.RawPlan.PlannedValues.Outputs {
+			if output.Sensitive {
+				snapshot.Outputs[name] = "(sensitive)"
+			} else if change, ok := plan.RawPlan.OutputChanges[name]; ok && change.AfterUnknown == true {
+				snapshot.Outputs[name] = "(known after apply)"
+			} else {
+				snapshot.Outputs[name] = output.Value
+			}
+		}
+	}
+
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return "", err
+	}
+
+	out := string(data)
+	for _, scrubber := range append(append([]Scrubber{}, DefaultScrubbers...), scrubbers...) {
+		out = scrubber.Pattern.ReplaceAllString(out, scrubber.Replacement)
+	}
+	return out + "\n", nil
+}
+
+// compact returns value without null attributes and empty lists or objects.
+func compact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := map[string]interface{}{}
+		for key, item := range v {
+			if item = compact(item); item != nil {
+				out[key] = item
+			}
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		return out
+	case []interface{}:
+		if len(v) == 0 {
+			return nil
+		}
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = compact(item)
+		}
+		return out
+	}
+	return value
+}
+
+// lineDiff returns a diff of two texts with up to diffContext unchanged lines
+// around each change, or "" when they are equal.
+func lineDiff(want string, got string) string {
+	if want == got {
+		return ""
+	}
+	a := strings.Split(want, "\n")
+	b := strings.Split(got, "\n")
+
+	// Longest common subsequence table, filled from the end.
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type line struct {
+		op   byte
+		text string
+	}
+	var lines []line
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, line{' ', a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, line{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, line{'+', b[j]})
+			j++
+		}
+	}
+
+	// Keep changed lines and the unchanged lines close to them.
+	keep := make([]bool, len(lines))
+	for k, l := range lines {
+		if l.op == ' ' {
+			continue
+		}
+		for c := max(0, k-diffContext); c <= min(len(lines)-1, k+diffContext); c++ {
+			keep[c] = true
+		}
+	}
+
+	var out strings.Builder
+	for k, l := range lines {
+		if !keep[k] {
+			if k == 0 || keep[k-1] {
+				out.WriteString("  ...\n")
+			}
+			continue
+		}
+		fmt.Fprintf(&out, "%c %s\n", l.op, l.text)
+	}
+	return out.String()
+}
+
+const diffContext = 3
@@ -0,0 +1,117 @@
+package terraformtest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testPlan() *terraform.PlanStruct {
+	return &terraform.PlanStruct{RawPlan: tfjson.Plan{
+		ResourceChanges: []*tfjson.ResourceChange{
+			{
+				Address: "google_storage_bucket.state_bucket",
+				Change: &tfjson.Change{
+					Actions: tfjson.Actions{tfjson.ActionCreate},
+					After: map[string]interface{}{
+						"name":     "test-project-ab12cd-terraform-state",
+						"location": "US",
+						"cors":     []interface{}{},
+						"logging":  nil,
+					},
+					AfterUnknown: map[string]interface{}{"self_link": true, "versioning": []interface{}{}},
+				},
+			},
+			{
+				Address: "google_project_service.apis[\"iam.googleapis.com\"]",
+				Change:  &tfjson.Change{Actions: tfjson.Actions{tfjson.ActionNoop}},
+			},
+		},
+		PlannedValues: &tfjson.StateValues{Outputs: map[string]*tfjson.StateOutput{
+			"created_at": {Value: "2024-05-01T10:11:12Z"},
+			"key":        {Value: "secret", Sensitive: true},
+			"self_link":  {},
+		}},
+		OutputChanges: map[string]*tfjson.Change{
+			"self_link": {Actions: tfjson.Actions{tfjson.ActionCreate}, AfterUnknown: true},
+		},
+	}}
+}
+
+func TestPlanSnapshot(t *testing.T) {
+	snapshot, err := PlanSnapshot(testPlan(), ScrubLiteral("test-project-ab12cd", "<project_id>"))
+	require.NoError(t, err)
+
+	assert.Equal(t, `{
+  "resource_changes": [
+    {
+      "address": "google_storage_bucket.state_bucket",
+      "actions": [
+        "create"
+      ],
+      "after": {
+        "location": "US",
+        "name": "<project_id>-terraform-state",
+        "self_link": "(known after apply)"
+      }
+    }
+  ],
+  "outputs": {
+    "created_at": "<timestamp>",
+    "key": "(sensitive)",
+    "self_link": "(known after apply)"
+  }
+}
+`, snapshot)
+}
+
+func TestAssertPlanMatchesGoldenUpdate(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "testdata", "state-backend.golden.json")
+
+	*updateGolden = true
+	AssertPlanMatchesGolden(t, testPlan(), golden)
+	*updateGolden = false
+	assert.FileExists(t, golden)
+
+	// Without -update the run compares against the file just written.
+	AssertPlanMatchesGolden(t, testPlan(), golden)
+}
+
+func TestAssertPlanMatchesGoldenFailsWhenMissing(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "missing.golden.json")
+
+	ft := &fakeT{}
+	ft.run(func() { AssertPlanMatchesGolden(ft, testPlan(), golden) })
+	assert.True(t, ft.failed)
+	assert.Contains(t, strings.Join(ft.messages, "\n"), "run the tests with -update")
+	assert.NoFileExists(t, golden)
+}
+
+func TestAssertPlanMatchesGoldenFailsOnDiff(t *testing.T) {
+	golden := filepath.Join(t.TempDir(), "state-backend.golden.json")
+	snapshot, err := PlanSnapshot(testPlan())
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(golden, []byte(strings.Replace(snapshot, `"US"`, `"EU"`, 1)), 0o644))
+
+	ft := &fakeT{}
+	ft.run(func() { AssertPlanMatchesGolden(ft, testPlan(), golden) })
+	assert.True(t, ft.failed)
+	message := strings.Join(ft.messages, "\n")
+	assert.Contains(t, message, "plan does not match golden file")
+	assert.Contains(t, message, `-         "location": "EU",`)
+	assert.Contains(t, message, `+         "location": "US",`)
+}
+
+func TestLineDiff(t *testing.T) {
+	assert.Empty(t, lineDiff("a\nb\nc", "a\nb\nc"))
+
+	want := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10"
+	got := "1\n2\n3\n4\n5\n6\n7\nEIGHT\n9\n10"
+	assert.Equal(t, "  ...\n  5\n  6\n  7\n- 8\n+ EIGHT\n  9\n  10\n", lineDiff(want, got))
+}
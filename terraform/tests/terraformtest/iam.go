@@ -0,0 +1,148 @@
+package terraformtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/require"
+)
+
+// PrimitiveRoles are the basic GCP roles that are never least-privilege.
+var PrimitiveRoles = []string{"roles/owner", "roles/editor", "roles/viewer"}
+
+// IAMGrant is a single role granted to a single member by a module resource.
+type IAMGrant struct {
+	Address string
+	Role    string
+	Member  string
+}
+
+// LeastPrivilegePolicy lists the roles a module is allowed to grant. Entries
+// ending in "*" match by prefix, e.g. "roles/storage.object*". Primitive roles
+// are rejected even when listed.
+type LeastPrivilegePolicy struct {
+	AllowedRoles []string
+}
+
+// AssertLeastPrivilege fails the test if the applied module grants any role
+// outside the policy, listing every unexpected grant.
+func AssertLeastPrivilege(t testing.TestingT, options *terraform.Options, policy LeastPrivilegePolicy) {
+	grants, err := IAMGrantsFromStateE(terraform.Show(t, options))
+	require.NoError(t, err)
+	require.NoError(t, policy.Check(grants))
+}
+
+// AssertPlanLeastPrivilege is AssertLeastPrivilege for a plan, so grants can
+// be verified before anything is created.
+func AssertPlanLeastPrivilege(t testing.TestingT, plan *terraform.PlanStruct, policy LeastPrivilegePolicy) {
+	require.NoError(t, policy.Check(IAMGrantsFromValues(plan.RawPlan.PlannedValues)))
+}
+
+// IAMGrantsFromStateE extracts the IAM grants from the output of
+// `terraform show -json` for an applied module.
+func IAMGrantsFromStateE(stateJSON string) ([]IAMGrant, error) {
+	values, err := StateValuesE(stateJSON)
+	if err != nil {
+		return nil, err
+	}
+	return IAMGrantsFromValues(values), nil
+}
+
+// IAMGrantsFromValues returns the grants made by the *_iam_member,
+// *_iam_binding, and *_iam_policy resources of a state or plan. Members that
+// are only known after apply are reported as "(known after apply)".
+func IAMGrantsFromValues(values *tfjson.StateValues) []IAMGrant {
+	var grants []IAMGrant
+	for _, resource := range ManagedResources(values) {
+		grants = append(grants, grantsOf(resource)...)
+	}
+	return grants
+}
+
+func grantsOf(resource *tfjson.StateResource) []IAMGrant {
+	attrs := resource.AttributeValues
+	role, _ := attrs["role"].(string)
+
+	switch {
+	case strings.HasSuffix(resource.Type, "_iam_member"):
+		return []IAMGrant{{Address: resource.Address, Role: role, Member: memberOf(attrs["member"])}}
+
+	case strings.HasSuffix(resource.Type, "_iam_binding"):
+		members, _ := attrs["members"].([]interface{})
+		if len(members) == 0 {
+			return []IAMGrant{{Address: resource.Address, Role: role, Member: memberOf(nil)}}
+		}
+		grants := make([]IAMGrant, 0, len(members))
+		for _, member := range members {
+			grants = append(grants, IAMGrant{Address: resource.Address, Role: role, Member: memberOf(member)})
+		}
+		return grants
+
+	case strings.HasSuffix(resource.Type, "_iam_policy"):
+		data, _ := attrs["policy_data"].(string)
+		var policy struct {
+			Bindings []struct {
+				Role    string   `json:"role"`
+				Members []string `json:"members"`
+			} `json:"bindings"`
+		}
+		if err := json.Unmarshal([]byte(data), &policy); err != nil {
+			return []IAMGrant{{Address: resource.Address, Role: "(known after apply)", Member: memberOf(nil)}}
+		}
+		var grants []IAMGrant
+		for _, binding := range policy.Bindings {
+			for _, member := range binding.Members {
+				grants = append(grants, IAMGrant{Address: resource.Address, Role: binding.Role, Member: member})
+			}
+		}
+		return grants
+	}
+
+	return nil
+}
+
+func memberOf(value interface{}) string {
+	if member, ok := value.(string); ok && member != "" {
+		return member
+	}
+	return "(known after apply)"
+}
+
+// Check returns an error describing every grant that violates the policy, one
+// line per grant in diff style.
+func (p LeastPrivilegePolicy) Check(grants []IAMGrant) error {
+	var unexpected []string
+	for _, grant := range grants {
+		if reason := p.violation(grant.Role); reason != "" {
+			unexpected = append(unexpected, fmt.Sprintf("+ %s  %s  (%s: %s)", grant.Role, grant.Member, grant.Address, reason))
+		}
+	}
+	if len(unexpected) == 0 {
+		return nil
+	}
+
+	sort.Strings(unexpected)
+	return fmt.Errorf("%d IAM grant(s) outside the least-privilege policy:\n%s", len(unexpected), strings.Join(unexpected, "\n"))
+}
+
+func (p LeastPrivilegePolicy) violation(role string) string {
+	for _, primitive := range PrimitiveRoles {
+		if role == primitive {
+			return "primitive role"
+		}
+	}
+	for _, allowed := range p.AllowedRoles {
+		if prefix, ok := strings.CutSuffix(allowed, "*"); ok && strings.HasPrefix(role, prefix) {
+			return ""
+		}
+		if role == allowed {
+			return ""
+		}
+	}
+	return "not in allow-list"
+}
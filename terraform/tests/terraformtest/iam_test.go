@@ -0,0 +1,93 @@
+package terraformtest
+
+import (
+	"testing"
+
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const iamStateJSON = `{
+  "format_version": "1.0",
+  "values": {
+    "root_module": {
+      "resources": [
+        {
+          "address": "google_project_iam_member.default_sa_roles[\"roles/editor\"]",
+          "mode": "managed",
+          "type": "google_project_iam_member",
+          "values": {"role": "roles/editor", "member": "serviceAccount:sa@demo.iam.gserviceaccount.com"}
+        },
+        {
+          "address": "google_storage_bucket.state_bucket",
+          "mode": "managed",
+          "type": "google_storage_bucket",
+          "values": {"name": "demo-terraform-state"}
+        }
+      ],
+      "child_modules": [
+        {
+          "address": "module.state_backend",
+          "resources": [
+            {
+              "address": "module.state_backend.google_storage_bucket_iam_binding.admins",
+              "mode": "managed",
+              "type": "google_storage_bucket_iam_binding",
+              "values": {"role": "roles/storage.objectAdmin", "members": ["user:a@example.com", "user:b@example.com"]}
+            },
+            {
+              "address": "module.state_backend.google_project_iam_policy.project",
+              "mode": "managed",
+              "type": "google_project_iam_policy",
+              "values": {"policy_data": "{\"bindings\":[{\"role\":\"roles/iam.securityAdmin\",\"members\":[\"group:sec@example.com\"]}]}"}
+            }
+          ]
+        }
+      ]
+    }
+  }
+}`
+
+func TestIAMGrantsFromState(t *testing.T) {
+	grants, err := IAMGrantsFromStateE(iamStateJSON)
+	require.NoError(t, err)
+
+	assert.Equal(t, []IAMGrant{
+		{Address: `google_project_iam_member.default_sa_roles["roles/editor"]`, Role: "roles/editor", Member: "serviceAccount:sa@demo.iam.gserviceaccount.com"},
+		{Address: "module.state_backend.google_storage_bucket_iam_binding.admins", Role: "roles/storage.objectAdmin", Member: "user:a@example.com"},
+		{Address: "module.state_backend.google_storage_bucket_iam_binding.admins", Role: "roles/storage.objectAdmin", Member: "user:b@example.com"},
+		{Address: "module.state_backend.google_project_iam_policy.project", Role: "roles/iam.securityAdmin", Member: "group:sec@example.com"},
+	}, grants)
+}
+
+func TestIAMGrantsUnknownMember(t *testing.T) {
+	grants := IAMGrantsFromValues(&tfjson.StateValues{RootModule: &tfjson.StateModule{
+		Resources: []*tfjson.StateResource{{
+			Address:         "google_project_iam_member.sa",
+			Mode:            tfjson.ManagedResourceMode,
+			Type:            "google_project_iam_member",
+			AttributeValues: map[string]interface{}{"role": "roles/logging.logWriter"},
+		}},
+	}})
+
+	require.Len(t, grants, 1)
+	assert.Equal(t, "(known after apply)", grants[0].Member)
+}
+
+func TestLeastPrivilegePolicyCheck(t *testing.T) {
+	grants, err := IAMGrantsFromStateE(iamStateJSON)
+	require.NoError(t, err)
+
+	// Primitive roles are rejected even when allow-listed.
+	policy := LeastPrivilegePolicy{AllowedRoles: []string{"roles/editor", "roles/storage.object*"}}
+	err = policy.Check(grants)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "2 IAM grant(s)")
+	assert.Contains(t, err.Error(), "+ roles/editor  serviceAccount:sa@demo.iam.gserviceaccount.com")
+	assert.Contains(t, err.Error(), "primitive role")
+	assert.Contains(t, err.Error(), "+ roles/iam.securityAdmin  group:sec@example.com")
+	assert.NotContains(t, err.Error(), "roles/storage.objectAdmin")
+
+	assert.NoError(t, LeastPrivilegePolicy{AllowedRoles: []string{"roles/storage.objectAdmin"}}.Check(grants[1:3]))
+}
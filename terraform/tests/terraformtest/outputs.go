@@ -0,0 +1,243 @@
+// Package terraformtest collects terratest helpers shared by the module
+// integration tests.
+package terraformtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// OutputKind is the coarse shape expected of an output value. Terraform
+// reports `[for ...]` expressions as tuples and `{for ...}` expressions as
+// objects, so kinds match on shape rather than on the exact type.
+type OutputKind string
+
+const (
+	KindAny    OutputKind = ""
+	KindString OutputKind = "string"
+	KindNumber OutputKind = "number"
+	KindBool   OutputKind = "bool"
+	KindList   OutputKind = "list" // list, set, or tuple
+	KindMap    OutputKind = "map"  // map or object
+)
+
+// OutputSpec is a single output a module promises to its callers.
+type OutputSpec struct {
+	Name        string
+	Description string
+	Sensitive   bool
+	Kind        OutputKind
+
+	// Optional outputs may be null or empty, e.g. values behind a create_* toggle.
+	Optional bool
+
+	declared bool
+}
+
+// OutputContract is the set of outputs declared by a module, refined by the
+// test with expected kinds and optional outputs.
+type OutputContract struct {
+	ModuleDir string
+	Outputs   map[string]*OutputSpec
+}
+
+// LoadOutputContract parses the output blocks of every .tf file in moduleDir.
+func LoadOutputContract(t testing.TestingT, moduleDir string) *OutputContract {
+	contract, err := LoadOutputContractE(moduleDir)
+	require.NoError(t, err)
+	return contract
+}
+
+// LoadOutputContractE parses the output blocks of every .tf file in moduleDir.
+func LoadOutputContractE(moduleDir string) (*OutputContract, error) {
+	files, err := filepath.Glob(filepath.Join(moduleDir, "*.tf"))
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no .tf files found in %s", moduleDir)
+	}
+
+	contract := &OutputContract{ModuleDir: moduleDir, Outputs: map[string]*OutputSpec{}}
+	parser := hclparse.NewParser()
+	schema := &hcl.BodySchema{
+		Blocks: []hcl.BlockHeaderSchema{{Type: "output", LabelNames: []string{"name"}}},
+	}
+	outputSchema := &hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{{Name: "description"}, {Name: "sensitive"}},
+	}
+
+	for _, path := range files {
+		file, diags := parser.ParseHCLFile(path)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+		content, _, diags := file.Body.PartialContent(schema)
+		if diags.HasErrors() {
+			return nil, diags
+		}
+
+		for _, block := range content.Blocks {
+			spec := &OutputSpec{Name: block.Labels[0], declared: true}
+			attrs, _, diags := block.Body.PartialContent(outputSchema)
+			if diags.HasErrors() {
+				return nil, diags
+			}
+			if attr, ok := attrs.Attributes["description"]; ok {
+				if v, diags := attr.Expr.Value(nil); !diags.HasErrors() && v.Type() == cty.String && v.IsKnown() && !v.IsNull() {
+					spec.Description = v.AsString()
+				}
+			}
+			if attr, ok := attrs.Attributes["sensitive"]; ok {
+				if v, diags := attr.Expr.Value(nil); !diags.HasErrors() && v.Type() == cty.Bool && v.IsKnown() && !v.IsNull() {
+					spec.Sensitive = v.True()
+				}
+			}
+			contract.Outputs[spec.Name] = spec
+		}
+	}
+
+	return contract, nil
+}
+
+// Expect sets the kind an output must have. Expecting an output the module
+// does not declare is itself a contract violation.
+func (c *OutputContract) Expect(name string, kind OutputKind) *OutputContract {
+	c.spec(name).Kind = kind
+	return c
+}
+
+// AllowNull marks outputs that may legitimately be null or empty after apply.
+func (c *OutputContract) AllowNull(names ...string) *OutputContract {
+	for _, name := range names {
+		c.spec(name).Optional = true
+	}
+	return c
+}
+
+func (c *OutputContract) spec(name string) *OutputSpec {
+	spec, ok := c.Outputs[name]
+	if !ok {
+		spec = &OutputSpec{Name: name}
+		c.Outputs[name] = spec
+	}
+	return spec
+}
+
+// AssertOutputContract fails the test if the applied module did not produce
+// every documented output, non-empty and of the expected kind.
+func AssertOutputContract(t testing.TestingT, options *terraform.Options, contract *OutputContract) {
+	require.NoError(t, VerifyOutputContractE(t, options, contract))
+}
+
+// VerifyOutputContractE checks the outputs of the applied module against the
+// contract and returns an error listing every violation.
+func VerifyOutputContractE(t testing.TestingT, options *terraform.Options, contract *OutputContract) error {
+	out, err := terraform.OutputJsonE(t, options, "")
+	if err != nil {
+		return err
+	}
+
+	actual := map[string]appliedOutput{}
+	if err := json.Unmarshal([]byte(out), &actual); err != nil {
+		return fmt.Errorf("parsing terraform output: %w", err)
+	}
+
+	return contract.check(actual)
+}
+
+// appliedOutput is one entry of `terraform output -json`.
+type appliedOutput struct {
+	Sensitive bool            `json:"sensitive"`
+	Type      json.RawMessage `json:"type"`
+	Value     interface{}     `json:"value"`
+}
+
+func (c *OutputContract) check(actual map[string]appliedOutput) error {
+	names := make([]string, 0, len(c.Outputs))
+	for name := range c.Outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var violations []string
+	for _, name := range names {
+		spec := c.Outputs[name]
+		if !spec.declared {
+			violations = append(violations, fmt.Sprintf("%s: expected by the test but not declared", name))
+			continue
+		}
+		if spec.Description == "" {
+			violations = append(violations, fmt.Sprintf("%s: missing description", name))
+		}
+
+		// Terraform does not store null outputs, so a null value shows up as
+		// a missing key rather than a JSON null.
+		got, ok := actual[name]
+		if !ok || isEmpty(got.Value) {
+			if !spec.Optional {
+				violations = append(violations, fmt.Sprintf("%s: null or empty after apply", name))
+			}
+			continue
+		}
+
+		if spec.Kind != KindAny {
+			if kind := kindOf(got.Type); kind != spec.Kind {
+				violations = append(violations, fmt.Sprintf("%s: expected %s, got %s", name, spec.Kind, got.Type))
+			}
+		}
+	}
+
+	if len(violations) > 0 {
+		return fmt.Errorf("output contract of %s violated:\n  %s", c.ModuleDir, strings.Join(violations, "\n  "))
+	}
+	return nil
+}
+
+// kindOf maps a JSON-encoded terraform type to an OutputKind.
+func kindOf(raw json.RawMessage) OutputKind {
+	var typ interface{}
+	if err := json.Unmarshal(raw, &typ); err != nil {
+		return KindAny
+	}
+
+	switch v := typ.(type) {
+	case string:
+		return OutputKind(v)
+	case []interface{}:
+		if len(v) == 0 {
+			return KindAny
+		}
+		switch v[0] {
+		case "list", "set", "tuple":
+			return KindList
+		case "map", "object":
+			return KindMap
+		}
+	}
+	return KindAny
+}
+
+func isEmpty(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []interface{}:
+		return len(v) == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package terraformtest
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadOutputContract(t *testing.T) {
+	contract := LoadOutputContract(t, "../../modules/service-accounts")
+
+	require.Contains(t, contract.Outputs, "service_account_emails")
+	assert.NotEmpty(t, contract.Outputs["service_account_emails"].Description)
+	assert.True(t, contract.Outputs["service_account_keys"].Sensitive)
+	assert.False(t, contract.Outputs["service_account_ids"].Sensitive)
+}
+
+func TestOutputContractCheck(t *testing.T) {
+	contract := LoadOutputContract(t, "../../modules/state-backend").
+		Expect("bucket_name", KindString).
+		Expect("bucket_url", KindString).
+		AllowNull("terraform_sa_email", "terraform_sa_key")
+
+	actual := map[string]appliedOutput{
+		"bucket_name":      {Type: json.RawMessage(`"string"`), Value: "demo-terraform-state"},
+		"bucket_url":       {Type: json.RawMessage(`"string"`), Value: "gs://demo-terraform-state"},
+		"bucket_self_link": {Type: json.RawMessage(`"string"`), Value: "https://example/demo"},
+	}
+	assert.NoError(t, contract.check(actual))
+
+	// Missing required output and a kind mismatch are both reported.
+	delete(actual, "bucket_self_link")
+	actual["bucket_url"] = appliedOutput{Type: json.RawMessage(`["tuple",["string"]]`), Value: []interface{}{"gs://x"}}
+	err := contract.check(actual)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bucket_self_link: null or empty after apply")
+	assert.Contains(t, err.Error(), "bucket_url: expected string")
+
+	// Expecting an output the module does not declare breaks the contract.
+	contract.Expect("bucket_id", KindString)
+	assert.Contains(t, contract.check(actual).Error(), "bucket_id: expected by the test but not declared")
+}
+
+func TestKindOf(t *testing.T) {
+	assert.Equal(t, KindString, kindOf(json.RawMessage(`"string"`)))
+	assert.Equal(t, KindList, kindOf(json.RawMessage(`["tuple",["string","string"]]`)))
+	assert.Equal(t, KindList, kindOf(json.RawMessage(`["set","string"]`)))
+	assert.Equal(t, KindMap, kindOf(json.RawMessage(`["object",{"a":"string"}]`)))
+	assert.Equal(t, KindMap, kindOf(json.RawMessage(`["map","string"]`)))
+}
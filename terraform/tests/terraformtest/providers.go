@@ -0,0 +1,150 @@
+package terraformtest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	gotesting "testing"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/stretchr/testify/require"
+)
+
+// providerOverrideFile is written into every module of the copied tree.
+// Terraform merges *_override.tf files last, so its required_providers entry
+// replaces the module's own constraint.
+const providerOverrideFile = "provider_version_override.tf"
+
+const lockFile = ".terraform.lock.hcl"
+
+// ProviderMatrix runs a module against several versions of one provider.
+type ProviderMatrix struct {
+	// Root is the folder containing the modules. It is copied for every
+	// version so modules referencing their siblings keep working.
+	Root string
+
+	// Module is the module under test, relative to Root.
+	Module string
+
+	// Provider is the local provider name and Source its registry address,
+	// e.g. "google" and "hashicorp/google".
+	Provider string
+	Source   string
+
+	// Versions are version constraints, e.g. "~> 5.0" and "~> 6.0".
+	Versions []string
+
+	Vars map[string]interface{}
+
+	// Budget, when set, meters the apply of AssertUpgradeSafe. Tests passed
+	// to Run that apply should meter themselves with Budget.Meter.
+	Budget *Budget
+}
+
+// Run runs test once per provider version as a parallel subtest, each
+// against its own copy of the modules pinned to that version.
+func (m ProviderMatrix) Run(t *gotesting.T, test func(t *gotesting.T, options *terraform.Options)) {
+	for _, version := range m.Versions {
+		version := version
+		t.Run(m.Provider+" "+version, func(t *gotesting.T) {
+			t.Parallel()
+			test(t, m.options(m.copy(t, version)))
+		})
+	}
+}
+
+// AssertUpgradeSafe applies the module with provider version from, then
+// upgrades to version to and fails the test if the resulting plan would
+// destroy or replace any resource. Everything is destroyed afterwards.
+func (m ProviderMatrix) AssertUpgradeSafe(t *gotesting.T, from string, to string) {
+	root := m.copy(t, from)
+	options := m.options(root)
+
+	defer terraform.Destroy(t, options)
+	defer m.Budget.Meter(t, options)()
+	terraform.InitAndApply(t, options)
+
+	// Apply reads PlanFilePath when set, so only set it for the upgrade plan
+	m.pin(t, root, to)
+	options.Upgrade = true
+	options.PlanFilePath = filepath.Join(t.TempDir(), "upgrade.plan")
+	plan := terraform.InitAndPlanAndShowWithStruct(t, options)
+
+	destructive := DestructiveChanges(plan)
+	require.Empty(t, destructive, "upgrading %s from %s to %s would destroy or replace resources", m.Provider, from, to)
+}
+
+// DestructiveChanges returns the addresses of resources the plan would
+// delete or replace, each with its planned actions.
+func DestructiveChanges(plan *terraform.PlanStruct) []string {
+	var destructive []string
+	for address, change := range plan.ResourceChangesMap {
+		if change.Change == nil {
+			continue
+		}
+		if actions := change.Change.Actions; actions.Delete() || actions.Replace() {
+			destructive = append(destructive, fmt.Sprintf("%s %v", address, actions))
+		}
+	}
+	sort.Strings(destructive)
+	return destructive
+}
+
+// copy copies Root to a temporary folder and pins every module in the copy
+// to version. The copy, including the providers downloaded into it, is
+// removed when the test ends.
+func (m ProviderMatrix) copy(t *gotesting.T, version string) string {
+	prefix := strings.NewReplacer("/", "-", " ", "-").Replace(t.Name())
+	root, err := files.CopyTerraformFolderToTemp(m.Root, prefix)
+	require.NoError(t, err)
+	// The copy lands in a subfolder named after Root, so remove its parent
+	t.Cleanup(func() { os.RemoveAll(filepath.Dir(root)) })
+	m.pin(t, root, version)
+	return root
+}
+
+func (m ProviderMatrix) options(root string) *terraform.Options {
+	return &terraform.Options{
+		TerraformDir: filepath.Join(root, m.Module),
+		Vars:         m.Vars,
+		NoColor:      true,
+	}
+}
+
+// pin writes the provider override into root and every module directly
+// under it, and removes their lock files: a lock taken for the module's own
+// constraint would fail `terraform init` against any other version.
+func (m ProviderMatrix) pin(t *gotesting.T, root string, version string) {
+	override := fmt.Sprintf(`terraform {
+  required_providers {
+    %s = {
+      source  = %q
+      version = %q
+    }
+  }
+}
+`, m.Provider, m.Source, version)
+
+	var modules []string
+	for _, pattern := range []string{"*.tf", filepath.Join("*", "*.tf")} {
+		matches, err := filepath.Glob(filepath.Join(root, pattern))
+		require.NoError(t, err)
+		modules = append(modules, matches...)
+	}
+
+	pinned := map[string]bool{}
+	for _, tf := range modules {
+		dir := filepath.Dir(tf)
+		if pinned[dir] {
+			continue
+		}
+		require.NoError(t, os.WriteFile(filepath.Join(dir, providerOverrideFile), []byte(override), 0o644))
+		if err := os.Remove(filepath.Join(dir, lockFile)); err != nil && !os.IsNotExist(err) {
+			require.NoError(t, err)
+		}
+		pinned[dir] = true
+	}
+}
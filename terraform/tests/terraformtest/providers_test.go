@@ -0,0 +1,53 @@
+package terraformtest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	tfjson "github.com/hashicorp/terraform-json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDestructiveChanges(t *testing.T) {
+	change := func(actions ...tfjson.Action) *tfjson.ResourceChange {
+		return &tfjson.ResourceChange{Change: &tfjson.Change{Actions: actions}}
+	}
+	plan := &terraform.PlanStruct{ResourceChangesMap: map[string]*tfjson.ResourceChange{
+		"google_storage_bucket.state_bucket":  change(tfjson.ActionDelete, tfjson.ActionCreate),
+		"google_service_account.terraform_sa": change(tfjson.ActionUpdate),
+		"google_project_service.apis":         change(tfjson.ActionNoop),
+		"google_storage_bucket_iam_member.sa": change(tfjson.ActionDelete),
+		"google_billing_budget.budget":        change(tfjson.ActionCreate, tfjson.ActionDelete),
+	}}
+
+	assert.Equal(t, []string{
+		"google_billing_budget.budget [create delete]",
+		"google_storage_bucket.state_bucket [delete create]",
+		"google_storage_bucket_iam_member.sa [delete]",
+	}, DestructiveChanges(plan))
+}
+
+func TestProviderMatrixCopyPinsEveryModule(t *testing.T) {
+	matrix := ProviderMatrix{Root: "../../modules", Module: "state-backend", Provider: "google", Source: "hashicorp/google"}
+	root := matrix.copy(t, "~> 6.0")
+	defer os.RemoveAll(filepath.Dir(root))
+
+	for _, module := range []string{"bootstrap", "project-setup", "service-accounts", "state-backend"} {
+		override, err := os.ReadFile(filepath.Join(root, module, providerOverrideFile))
+		require.NoError(t, err, module)
+		assert.Contains(t, string(override), `version = "~> 6.0"`)
+		assert.Contains(t, string(override), `source  = "hashicorp/google"`)
+
+		// The committed lock pins google 5.x and would fail init against ~> 6.0
+		require.FileExists(t, filepath.Join("../../modules", module, lockFile), module)
+		assert.NoFileExists(t, filepath.Join(root, module, lockFile), module)
+	}
+	assert.Equal(t, filepath.Join(root, "state-backend"), matrix.options(root).TerraformDir)
+
+	// The override must still parse as HCL alongside the module.
+	_, err := LoadOutputContractE(matrix.options(root).TerraformDir)
+	assert.NoError(t, err)
+}
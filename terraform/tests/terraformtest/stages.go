@@ -0,0 +1,257 @@
+package terraformtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+
+	"github.com/gruntwork-io/terratest/modules/files"
+	"github.com/gruntwork-io/terratest/modules/logger"
+	"github.com/gruntwork-io/terratest/modules/terraform"
+	"github.com/gruntwork-io/terratest/modules/testing"
+	"github.com/stretchr/testify/require"
+)
+
+// KeepStagesEnvVar leaves applied stages in place after the test so a failing
+// later stage can be rerun without re-applying the stages before it.
+const KeepStagesEnvVar = "KEEP_STAGES"
+
+// Stage is one module in a composition chain.
+type Stage struct {
+	Name string
+
+	// Dir is the module directory, relative to the chain's Root. The stage's
+	// state lives there, so stages must not share a directory.
+	Dir string
+
+	// Vars are passed to the module as-is.
+	Vars map[string]interface{}
+
+	// Inputs maps a variable of this stage to an output of an earlier stage,
+	// written as "<stage>.<output>".
+	Inputs map[string]string
+}
+
+// Chain applies a sequence of modules where the outputs of one stage feed the
+// inputs of the next, e.g. bootstrap → state-backend → networking → compute.
+//
+// Each stage keeps its own state in a stable working directory. A stage whose
+// resolved variables match the last successful apply is not applied again, so
+// a failure in a later stage can be fixed and rerun cheaply. Edits to a
+// stage's module alone do not trigger a re-apply; run Destroy first.
+//
+// Destroy tears down every stage that was applied, even partially, in reverse
+// order. Tests usually defer it, so after a failure every earlier stage is
+// destroyed too and the next run starts from scratch: reuse across runs only
+// happens with KEEP_STAGES set, which makes Destroy leave the stages in place.
+type Chain struct {
+	Name string
+
+	// Root is the folder containing every stage's module. It is copied into
+	// WorkDir as a whole so modules referencing their siblings keep working.
+	Root string
+
+	// WorkDir holds the copied modules and stage records. Defaults to a
+	// directory under os.TempDir() named after the chain.
+	WorkDir string
+
+	Stages []Stage
+
+	// Budget, when set, meters every stage applied; see Budget.Meter.
+	Budget *Budget
+
+	outputs map[string]map[string]interface{}
+	meters  []func()
+}
+
+// stageRecord is persisted before a stage is applied, so Destroy can find a
+// stage whose apply failed partway, and completed once the apply succeeds.
+type stageRecord struct {
+	Vars    map[string]interface{} `json:"vars"`
+	Outputs map[string]interface{} `json:"outputs"`
+	Applied bool                   `json:"applied"`
+}
+
+// NewChain creates a chain of stages whose modules live under root.
+func NewChain(name string, root string, stages ...Stage) *Chain {
+	return &Chain{
+		Name:    name,
+		Root:    root,
+		WorkDir: filepath.Join(os.TempDir(), "terratest-chain-"+name),
+		Stages:  stages,
+	}
+}
+
+// Apply applies every stage in order, skipping stages already applied with
+// the same variables, and returns the outputs of all stages by stage name.
+func (c *Chain) Apply(t testing.TestingT) map[string]map[string]interface{} {
+	require.NoError(t, os.MkdirAll(c.sourceDir(), 0o755))
+	require.NoError(t, files.CopyFolderContentsWithFilter(c.Root, c.sourceDir(), copyFilter))
+	c.outputs = map[string]map[string]interface{}{}
+
+	for _, stage := range c.Stages {
+		vars, err := c.resolveVars(stage)
+		require.NoError(t, err)
+
+		if record, ok := c.reusable(stage, vars); ok {
+			logger.Default.Logf(t, "Stage %s unchanged since last apply, reusing its outputs", stage.Name)
+			c.outputs[stage.Name] = record.Outputs
+			continue
+		}
+
+		logger.Default.Logf(t, "Applying stage %s", stage.Name)
+		options := c.options(stage, vars)
+		c.meters = append(c.meters, c.Budget.Meter(t, options))
+		require.NoError(t, c.saveRecord(stage, stageRecord{Vars: normalizeVars(vars)}))
+		terraform.InitAndApply(t, options)
+		outputs := terraform.OutputAll(t, options)
+		require.NoError(t, c.saveRecord(stage, stageRecord{Vars: normalizeVars(vars), Outputs: outputs, Applied: true}))
+		c.outputs[stage.Name] = outputs
+	}
+
+	return c.outputs
+}
+
+// Outputs returns the outputs of an applied stage.
+func (c *Chain) Outputs(stage string) map[string]interface{} {
+	return c.outputs[stage]
+}
+
+// Destroy tears down every stage with a recorded apply in reverse order,
+// including a stage whose apply failed partway. A failing stage does not stop
+// the teardown of the stages before it. The stages are left in place when
+// KEEP_STAGES is set. Either way, the cost of the stages applied in this run
+// is recorded first when the chain has a Budget.
+func (c *Chain) Destroy(t testing.TestingT) {
+	// Tear down even when a meter fails the test for crossing the budget
+	defer c.destroy(t)
+
+	for i := len(c.meters) - 1; i >= 0; i-- {
+		c.meters[i]()
+	}
+	c.meters = nil
+}
+
+func (c *Chain) destroy(t testing.TestingT) {
+	if os.Getenv(KeepStagesEnvVar) != "" {
+		logger.Default.Logf(t, "%s is set, leaving stages of %s in %s", KeepStagesEnvVar, c.Name, c.WorkDir)
+		return
+	}
+
+	var failed []string
+	for i := len(c.Stages) - 1; i >= 0; i-- {
+		stage := c.Stages[i]
+		record, ok := c.loadRecord(stage)
+		if !ok {
+			continue
+		}
+
+		logger.Default.Logf(t, "Destroying stage %s", stage.Name)
+		if _, err := terraform.DestroyE(t, c.options(stage, record.Vars)); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", stage.Name, err))
+			continue
+		}
+		os.Remove(c.recordPath(stage))
+	}
+
+	require.Empty(t, failed, "teardown of chain %s failed", c.Name)
+}
+
+// resolveVars merges a stage's static variables with its inputs from
+// earlier stages.
+func (c *Chain) resolveVars(stage Stage) (map[string]interface{}, error) {
+	vars := map[string]interface{}{}
+	for k, v := range stage.Vars {
+		vars[k] = v
+	}
+
+	for variable, ref := range stage.Inputs {
+		from, output, ok := strings.Cut(ref, ".")
+		if !ok {
+			return nil, fmt.Errorf("stage %s: input %s must be written <stage>.<output>, got %q", stage.Name, variable, ref)
+		}
+		outputs, ok := c.outputs[from]
+		if !ok {
+			return nil, fmt.Errorf("stage %s: input %s refers to stage %s, which has not been applied before it", stage.Name, variable, from)
+		}
+		value, ok := outputs[output]
+		if !ok {
+			return nil, fmt.Errorf("stage %s: input %s refers to missing output %s", stage.Name, variable, ref)
+		}
+		vars[variable] = value
+	}
+
+	return vars, nil
+}
+
+func (c *Chain) options(stage Stage, vars map[string]interface{}) *terraform.Options {
+	return &terraform.Options{
+		TerraformDir: filepath.Join(c.sourceDir(), stage.Dir),
+		Vars:         vars,
+		NoColor:      true,
+	}
+}
+
+func (c *Chain) sourceDir() string {
+	return filepath.Join(c.WorkDir, "src")
+}
+
+func (c *Chain) recordPath(stage Stage) string {
+	return filepath.Join(c.WorkDir, stage.Name+".json")
+}
+
+// reusable returns the record of a stage that was applied successfully with
+// the same variables.
+func (c *Chain) reusable(stage Stage, vars map[string]interface{}) (stageRecord, bool) {
+	record, ok := c.loadRecord(stage)
+	if !ok || !record.Applied || !reflect.DeepEqual(normalizeVars(vars), record.Vars) {
+		return stageRecord{}, false
+	}
+	return record, true
+}
+
+func (c *Chain) loadRecord(stage Stage) (stageRecord, bool) {
+	var record stageRecord
+	data, err := os.ReadFile(c.recordPath(stage))
+	if err != nil {
+		return record, false
+	}
+	if err := json.Unmarshal(data, &record); err != nil {
+		return record, false
+	}
+	return record, true
+}
+
+func (c *Chain) saveRecord(stage Stage, record stageRecord) error {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.recordPath(stage), data, 0o644)
+}
+
+// normalizeVars round-trips vars through JSON so they compare equal to a
+// record loaded from disk.
+func normalizeVars(vars map[string]interface{}) map[string]interface{} {
+	data, err := json.Marshal(vars)
+	if err != nil {
+		return vars
+	}
+	normalized := map[string]interface{}{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return vars
+	}
+	return normalized
+}
+
+// copyFilter skips state, tfvars, and hidden files such as .terraform so that
+// refreshing the copied modules never clobbers a stage's state.
+func copyFilter(path string) bool {
+	if files.PathIsTerraformLockFile(path) {
+		return true
+	}
+	return !files.PathContainsHiddenFileOrFolder(path) && !files.PathContainsTerraformStateOrVars(path)
+}
@@ -0,0 +1,71 @@
+package terraformtest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainResolveVars(t *testing.T) {
+	chain := NewChain("unit", "../../modules")
+	chain.outputs = map[string]map[string]interface{}{
+		"bootstrap": {"project_id": "demo-project"},
+	}
+
+	vars, err := chain.resolveVars(Stage{
+		Name:   "state-backend",
+		Vars:   map[string]interface{}{"location": "US"},
+		Inputs: map[string]string{"project_id": "bootstrap.project_id"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"location": "US", "project_id": "demo-project"}, vars)
+
+	_, err = chain.resolveVars(Stage{Name: "compute", Inputs: map[string]string{"network": "networking.self_link"}})
+	assert.ErrorContains(t, err, "has not been applied before it")
+
+	_, err = chain.resolveVars(Stage{Name: "compute", Inputs: map[string]string{"project_id": "bootstrap.project_number"}})
+	assert.ErrorContains(t, err, "missing output bootstrap.project_number")
+
+	_, err = chain.resolveVars(Stage{Name: "compute", Inputs: map[string]string{"project_id": "project_id"}})
+	assert.ErrorContains(t, err, "<stage>.<output>")
+}
+
+func TestChainRecordRoundTrip(t *testing.T) {
+	chain := NewChain("unit", "../../modules")
+	chain.WorkDir = t.TempDir()
+	stage := Stage{Name: "state-backend"}
+
+	_, ok := chain.loadRecord(stage)
+	assert.False(t, ok)
+
+	vars := map[string]interface{}{"max_versions": 5, "labels": map[string]string{"team": "infra"}}
+	require.NoError(t, chain.saveRecord(stage, stageRecord{Vars: normalizeVars(vars)}))
+
+	record, ok := chain.loadRecord(stage)
+	require.True(t, ok)
+	assert.Equal(t, normalizeVars(vars), record.Vars)
+}
+
+func TestChainReusesOnlyCompletedApplies(t *testing.T) {
+	chain := NewChain("unit", "../../modules")
+	chain.WorkDir = t.TempDir()
+	stage := Stage{Name: "state-backend"}
+	vars := map[string]interface{}{"project_id": "demo"}
+
+	// Saved before InitAndApply; an apply that fails partway leaves only this
+	require.NoError(t, chain.saveRecord(stage, stageRecord{Vars: normalizeVars(vars)}))
+	_, ok := chain.reusable(stage, vars)
+	assert.False(t, ok)
+	_, ok = chain.loadRecord(stage)
+	assert.True(t, ok, "Destroy must still find the partially applied stage")
+
+	outputs := map[string]interface{}{"bucket_name": "demo-terraform-state"}
+	require.NoError(t, chain.saveRecord(stage, stageRecord{Vars: normalizeVars(vars), Outputs: outputs, Applied: true}))
+	record, ok := chain.reusable(stage, vars)
+	require.True(t, ok)
+	assert.Equal(t, outputs, record.Outputs)
+
+	_, ok = chain.reusable(stage, map[string]interface{}{"project_id": "other"})
+	assert.False(t, ok)
+}
@@ -0,0 +1,45 @@
+package terraformtest
+
+import (
+	"encoding/json"
+	"fmt"
+
+	tfjson "github.com/hashicorp/terraform-json"
+)
+
+// StateValuesE decodes the values of the output of `terraform show -json`
+// for an applied module. Only the values are decoded so callers do not depend
+// on the state format versions known to terraform-json.
+func StateValuesE(stateJSON string) (*tfjson.StateValues, error) {
+	var state struct {
+		Values *tfjson.StateValues `json:"values"`
+	}
+	if err := json.Unmarshal([]byte(stateJSON), &state); err != nil {
+		return nil, fmt.Errorf("parsing terraform state: %w", err)
+	}
+	return state.Values, nil
+}
+
+// ManagedResources returns every managed resource of a state or plan,
+// including those of child modules. Data sources are left out.
+func ManagedResources(values *tfjson.StateValues) []*tfjson.StateResource {
+	if values == nil || values.RootModule == nil {
+		return nil
+	}
+
+	var resources []*tfjson.StateResource
+	var walk func(module *tfjson.StateModule)
+	walk = func(module *tfjson.StateModule) {
+		for _, resource := range module.Resources {
+			if resource.Mode != tfjson.DataResourceMode {
+				resources = append(resources, resource)
+			}
+		}
+		for _, child := range module.ChildModules {
+			walk(child)
+		}
+	}
+	walk(values.RootModule)
+
+	return resources
+}